@@ -0,0 +1,66 @@
+package addrs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// InputVariable is the address of an input variable declaration within a
+// module, relative to that module's root.
+type InputVariable struct {
+	referenceable
+	Name string
+}
+
+func (v InputVariable) String() string {
+	return "var." + v.Name
+}
+
+// Absolute converts the receiver into an absolute address within the
+// given module instance.
+func (v InputVariable) Absolute(m ModuleInstance) AbsInputVariableInstance {
+	return AbsInputVariableInstance{
+		Module:   m,
+		Variable: v,
+	}
+}
+
+// AbsInputVariableInstance is the address of a particular input variable
+// within a particular module instance.
+//
+// This is the key used to record and look up a variable's resolved value
+// during graph evaluation, replacing the former convention of pairing a
+// "Module string" with a synthetic "var.<name>" / "module.x.var.y" string.
+type AbsInputVariableInstance struct {
+	Module   ModuleInstance
+	Variable InputVariable
+}
+
+func (v AbsInputVariableInstance) String() string {
+	if v.Module.IsRoot() {
+		return v.Variable.String()
+	}
+	return fmt.Sprintf("%s.%s", v.Module.String(), v.Variable.String())
+}
+
+// ParseAbsInputVariableInstanceStr parses a dotted variable address, such
+// as "var.foo" or "module.bar.var.foo", by splitting it on "." and
+// resolving the "module...." prefix (if any) with ParseModuleInstanceStr.
+//
+// It accepts both root-module addresses like "var.foo" and module-scoped
+// addresses like "module.bar.var.foo" or "module.bar[\"a\"].var.foo".
+func ParseAbsInputVariableInstanceStr(s string) (AbsInputVariableInstance, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 || parts[len(parts)-2] != "var" {
+		return AbsInputVariableInstance{}, fmt.Errorf("invalid input variable address %q", s)
+	}
+	name := parts[len(parts)-1]
+	modulePart := strings.Join(parts[:len(parts)-2], ".")
+
+	modInst, err := ParseModuleInstanceStr(modulePart)
+	if err != nil {
+		return AbsInputVariableInstance{}, fmt.Errorf("invalid module path in %q: %s", s, err)
+	}
+
+	return InputVariable{Name: name}.Absolute(modInst), nil
+}
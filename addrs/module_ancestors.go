@@ -0,0 +1,62 @@
+package addrs
+
+// Ancestors returns a slice containing the receiver and every module it is
+// nested within, ordered shallowest (the root module) to deepest (the
+// receiver itself).
+//
+// This is the natural primitive for any operation that needs to consider
+// "this module and everywhere above it", such as evaluating a variable in
+// the calling module's scope or checking whether any enclosing module is
+// targeted.
+func (m Module) Ancestors() []Module {
+	ret := make([]Module, 0, len(m)+1)
+	for i := 0; i <= len(m); i++ {
+		ret = append(ret, append(Module{}, m[:i]...))
+	}
+	return ret
+}
+
+// Equal returns true if the receiver and other represent the same module
+// path.
+func (m Module) Equal(other Module) bool {
+	if len(m) != len(other) {
+		return false
+	}
+	for i := range m {
+		if m[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Ancestors returns a slice containing the receiver and every module
+// instance it is nested within, ordered shallowest (the root module) to
+// deepest (the receiver itself). See Module.Ancestors for more details.
+func (m ModuleInstance) Ancestors() []ModuleInstance {
+	ret := make([]ModuleInstance, 0, len(m)+1)
+	for i := 0; i <= len(m); i++ {
+		ret = append(ret, append(ModuleInstance{}, m[:i]...))
+	}
+	return ret
+}
+
+// Equal returns true if the receiver and other represent the same module
+// instance path, including instance keys.
+func (m ModuleInstance) Equal(other ModuleInstance) bool {
+	if len(m) != len(other) {
+		return false
+	}
+	for i := range m {
+		if m[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// IsRoot returns true if the receiver is the root module instance, i.e.
+// the address with no steps.
+func (m ModuleInstance) IsRoot() bool {
+	return m.Equal(ModuleInstance{})
+}
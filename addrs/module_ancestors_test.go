@@ -0,0 +1,69 @@
+package addrs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestModuleInstanceAncestors(t *testing.T) {
+	m := ModuleInstance{
+		{Name: "a"},
+		{Name: "b", Key: StringKey("x")},
+	}
+
+	got := m.Ancestors()
+	want := []ModuleInstance{
+		{},
+		{{Name: "a"}},
+		{{Name: "a"}, {Name: "b", Key: StringKey("x")}},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong result\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestModuleInstanceEqual(t *testing.T) {
+	tests := []struct {
+		a, b ModuleInstance
+		want bool
+	}{
+		{ModuleInstance{}, ModuleInstance{}, true},
+		{
+			ModuleInstance{{Name: "a"}},
+			ModuleInstance{{Name: "a"}},
+			true,
+		},
+		{
+			ModuleInstance{{Name: "a"}},
+			ModuleInstance{{Name: "b"}},
+			false,
+		},
+		{
+			ModuleInstance{{Name: "a", Key: StringKey("x")}},
+			ModuleInstance{{Name: "a"}},
+			false,
+		},
+		{
+			ModuleInstance{{Name: "a"}},
+			ModuleInstance{{Name: "a"}, {Name: "b"}},
+			false,
+		},
+	}
+
+	for _, test := range tests {
+		got := test.a.Equal(test.b)
+		if got != test.want {
+			t.Errorf("%#v.Equal(%#v) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestModuleInstanceIsRoot(t *testing.T) {
+	if !(ModuleInstance{}).IsRoot() {
+		t.Errorf("empty ModuleInstance should be root")
+	}
+	if (ModuleInstance{{Name: "a"}}).IsRoot() {
+		t.Errorf("non-empty ModuleInstance should not be root")
+	}
+}
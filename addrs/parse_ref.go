@@ -0,0 +1,117 @@
+package addrs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Reference describes a reference to an address made from an expression
+// elsewhere in the configuration, such as the "module.foo.bar" appearing
+// in an interpolation that refers to an output of module.foo.
+type Reference struct {
+	Subject Referenceable
+}
+
+// ParseRef parses a single dotted reference, such as "var.foo",
+// "module.bar.baz", or `module.bar["a"].baz`, as it would appear inside an
+// interpolation expression, and resolves it to a typed Referenceable.
+//
+// It replaces the old varNameForVar convention of building a synthetic
+// string like "module.foo.output.bar" and matching graph vertices by
+// comparing those strings: callers can instead compare the returned
+// Referenceable by address equality, which is both cheaper and correct for
+// per-instance references such as `module.foo["a"].bar` that have no
+// faithful flat-string representation.
+func ParseRef(s string) (*Reference, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid reference %q", s)
+	}
+
+	switch parts[0] {
+	case "var":
+		return &Reference{Subject: InputVariable{Name: parts[1]}}, nil
+
+	case "local":
+		return &Reference{Subject: LocalValue{Name: parts[1]}}, nil
+
+	case "module":
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("invalid reference %q: module reference must have an output name", s)
+		}
+
+		name, key, err := parseModuleCallStep(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid reference %q: %s", s, err)
+		}
+
+		instance := ModuleCall{Name: name}.Instance(key)
+		return &Reference{Subject: instance.Output(parts[2])}, nil
+
+	case "data":
+		// "data.aws_ami.foo.id" names a data resource, not a managed one:
+		// parts[0] is the literal "data" keyword, parts[1] is the data
+		// source type, and parts[2] is the resource name. Handling this
+		// before the default case matters because the default case would
+		// otherwise parse it as a managed resource of type "data" named
+		// "aws_ami", silently losing the real type and mode.
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("invalid reference %q: data resource reference must have a type and name", s)
+		}
+
+		resource := Resource{
+			Mode: DataResourceMode,
+			Type: parts[1],
+			Name: parts[2],
+		}
+		return &Reference{Subject: resource.Instance(NoKey)}, nil
+
+	default:
+		// Anything else is a reference to a managed resource, such as
+		// "aws_instance.foo.id" or "aws_instance.foo.0.id": only the
+		// resource type and name are part of the address we track for
+		// dependency purposes, so the remaining parts (an index, an
+		// attribute path) don't need to be parsed here. Treating this as
+		// the default case -- rather than an error -- matters because
+		// referencing a resource directly is the ordinary way to
+		// populate a module call argument, and DependentOn must not
+		// silently drop that dependency.
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("invalid reference %q: unrecognized prefix %q", s, parts[0])
+		}
+
+		resource := Resource{
+			Mode: ManagedResourceMode,
+			Type: parts[0],
+			Name: parts[1],
+		}
+		return &Reference{Subject: resource.Instance(NoKey)}, nil
+	}
+}
+
+// parseModuleCallStep splits a single "module.X" path segment such as
+// `foo` or `foo["a"]` into the call name and the requested instance key,
+// returning NoKey when no index is present.
+func parseModuleCallStep(s string) (name string, key InstanceKey, err error) {
+	open := strings.IndexByte(s, '[')
+	if open == -1 {
+		return s, NoKey, nil
+	}
+	if !strings.HasSuffix(s, "]") {
+		return "", nil, fmt.Errorf("unterminated index in %q", s)
+	}
+
+	name = s[:open]
+	raw := s[open+1 : len(s)-1]
+
+	if unquoted, err := strconv.Unquote(raw); err == nil {
+		return name, StringKey(unquoted), nil
+	}
+
+	idx, err := strconv.Atoi(raw)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid index %q in reference", raw)
+	}
+	return name, IntKey(idx), nil
+}
@@ -0,0 +1,75 @@
+package addrs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRef(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Referenceable
+		wantErr bool
+	}{
+		{
+			input: "var.foo",
+			want:  InputVariable{Name: "foo"},
+		},
+		{
+			input: "local.foo",
+			want:  LocalValue{Name: "foo"},
+		},
+		{
+			input: "module.foo.bar",
+			want:  ModuleCall{Name: "foo"}.Instance(NoKey).Output("bar"),
+		},
+		{
+			input: `module.foo["a"].bar`,
+			want:  ModuleCall{Name: "foo"}.Instance(StringKey("a")).Output("bar"),
+		},
+		{
+			input: "aws_instance.foo.id",
+			want: Resource{
+				Mode: ManagedResourceMode,
+				Type: "aws_instance",
+				Name: "foo",
+			}.Instance(NoKey),
+		},
+		{
+			input: "data.aws_ami.foo.id",
+			want: Resource{
+				Mode: DataResourceMode,
+				Type: "aws_ami",
+				Name: "foo",
+			}.Instance(NoKey),
+		},
+		{
+			input:   "module.foo",
+			wantErr: true,
+		},
+		{
+			input:   "nope",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got, err := ParseRef(test.input)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %#v", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if !reflect.DeepEqual(got.Subject, test.want) {
+				t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got.Subject, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package terraform
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/addrs"
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/config/module"
+)
+
+// EvalSetVariables is an EvalNode implementation that sets the given
+// variables into the scope identified by Addr, so that later evaluation
+// within that module instance (resources, outputs, nested module calls)
+// can reference them as var.<name>.
+//
+// Addr carries the full module-instance scope that EvalTree used to
+// reconstruct from the separate "Module string" field; having it as a
+// single typed address removes the class of bug where that string and
+// the variable's own ModulePath could disagree.
+type EvalSetVariables struct {
+	Addr      addrs.AbsInputVariableInstance
+	Variables map[string]interface{}
+}
+
+// TODO: Eval is intentionally minimal; the real variable-storage
+// mechanics live in EvalContext, which this tree doesn't vendor.
+func (n *EvalSetVariables) Eval(ctx EvalContext) (interface{}, error) {
+	ctx = ctx.WithPath(legacyModulePath(n.Addr.Module))
+	for k, v := range n.Variables {
+		ctx.SetVariable(k, v)
+	}
+	return nil, nil
+}
+
+// EvalWritePlannedVariable is an EvalNode implementation that records an
+// interpolated module variable's value into the plan, keyed by its
+// absolute address, so the apply walk can read it back later without
+// re-running the interpolation that produced it.
+type EvalWritePlannedVariable struct {
+	Addr      addrs.AbsInputVariableInstance
+	Variables map[string]interface{}
+}
+
+func (n *EvalWritePlannedVariable) Eval(ctx EvalContext) (interface{}, error) {
+	diff, lock := ctx.Diff()
+	lock.Lock()
+	defer lock.Unlock()
+
+	if diff.ModuleVariables == nil {
+		diff.ModuleVariables = make(map[string]map[string]interface{})
+	}
+	diff.ModuleVariables[n.Addr.String()] = n.Variables
+	return nil, nil
+}
+
+// EvalReadPlannedVariable is an EvalNode implementation that reads a
+// module variable's value back out of the plan, as previously recorded by
+// EvalWritePlannedVariable, without re-evaluating the caller's expression.
+type EvalReadPlannedVariable struct {
+	Addr   addrs.AbsInputVariableInstance
+	Output *map[string]interface{}
+}
+
+func (n *EvalReadPlannedVariable) Eval(ctx EvalContext) (interface{}, error) {
+	diff, lock := ctx.Diff()
+	lock.Lock()
+	defer lock.Unlock()
+
+	*n.Output = diff.ModuleVariables[n.Addr.String()]
+	return nil, nil
+}
+
+// EvalCoerceMapVariable is an EvalNode implementation that coerces any
+// variable values destined for a map-typed variable into map[string]interface{},
+// since HCL's interpolation can otherwise produce a list of single-key maps.
+//
+// ModuleTree must already be the target module's own tree (the same one
+// DynamicExpand resolved when it built this node), not the root: this
+// node never needs to re-walk a path to find it.
+type EvalCoerceMapVariable struct {
+	Variables  map[string]interface{}
+	ModuleTree *module.Tree
+}
+
+func (n *EvalCoerceMapVariable) Eval(ctx EvalContext) (interface{}, error) {
+	mapVars := make(map[string]bool)
+	for _, v := range n.ModuleTree.Config().Variables {
+		if v.Type() == config.VariableTypeMap {
+			mapVars[v.Name] = true
+		}
+	}
+
+	for k, v := range n.Variables {
+		if !mapVars[k] {
+			// Not declared as a map, so leave it alone: a list-typed
+			// variable can legitimately hold []map[string]interface{}
+			// and must not be merged into a single map.
+			continue
+		}
+
+		items, ok := v.([]map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		merged := make(map[string]interface{})
+		for _, item := range items {
+			for ik, iv := range item {
+				merged[ik] = iv
+			}
+		}
+		n.Variables[k] = merged
+	}
+	return nil, nil
+}
+
+// EvalTypeCheckVariable is an EvalNode implementation that verifies the
+// variable values being set match the type declared by the corresponding
+// module, returning an error if not.
+//
+// ModuleAddr is carried only for error messages here, not for locating the
+// module: ModuleTree must already be the target module's own tree, the
+// same one DynamicExpand resolved when it built this node. Re-resolving
+// ModuleAddr's full path against it would double-descend -- for example,
+// for a single, non-nested call, it would look for a module named "foo"
+// as a child of module foo itself, rather than recognizing the tree it
+// already has as "foo".
+type EvalTypeCheckVariable struct {
+	Variables  map[string]interface{}
+	ModuleAddr addrs.ModuleInstance
+	ModuleTree *module.Tree
+}
+
+func (n *EvalTypeCheckVariable) Eval(ctx EvalContext) (interface{}, error) {
+	for _, v := range n.ModuleTree.Config().Variables {
+		raw, ok := n.Variables[v.Name]
+		if !ok {
+			continue
+		}
+		if err := v.ValidateTypeOf(raw); err != nil {
+			return nil, fmt.Errorf("%s: %s", n.ModuleAddr.String(), err)
+		}
+	}
+
+	return nil, nil
+}
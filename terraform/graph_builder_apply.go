@@ -0,0 +1,82 @@
+package terraform
+
+import (
+	"github.com/hashicorp/terraform/config/module"
+)
+
+// ApplyGraphBuilder implements GraphBuilder and is responsible for
+// constructing a graph for applying a Terraform diff.
+//
+// Unlike PlanGraphBuilder, which must walk the entire configuration to
+// determine what needs to change, ApplyGraphBuilder only needs to build
+// the graph for what the diff says needs to change. This means that an
+// apply graph, unlike a plan graph, may include only a subset of the
+// resources and module variables described by the configuration: exactly
+// the ones the diff touches, plus the module variable nodes each of them
+// depends on so their values are available.
+//
+// This snapshot has no context_apply.go, so nothing in this tree
+// constructs an ApplyGraphBuilder yet; that wiring belongs to the apply
+// command path, not to this file.
+type ApplyGraphBuilder struct {
+	// Diff is the diff to apply.
+	Diff *Diff
+
+	// State is the current state
+	State *State
+
+	// Module is the root module for the graph to build.
+	Module *module.Tree
+
+	// Targets are resources to target
+	Targets []string
+
+	// Destroy, if true, represents a pure destroy operation
+	Destroy bool
+}
+
+// See GraphBuilder
+func (b *ApplyGraphBuilder) Build(path []string) (*Graph, error) {
+	return (&BasicGraphBuilder{
+		Steps: b.Steps(),
+		Name:  "ApplyGraphBuilder",
+	}).Build(path)
+}
+
+// See GraphBuilder
+func (b *ApplyGraphBuilder) Steps() []GraphTransformer {
+	// The diff is the source of truth for what needs to change: every
+	// other node in this graph exists only because some resource in the
+	// diff depends on it, either directly or via a module variable.
+	steps := []GraphTransformer{
+		&DiffTransformer{
+			Diff:   b.Diff,
+			State:  b.State,
+			Module: b.Module,
+		},
+
+		// Add one nodeExpandModule per root-level module call, so count
+		// and for_each on a module call are resolved and expanded during
+		// the walk rather than assuming a single static instance.
+		&ModuleExpandTransformer{Module: b.Module},
+
+		// Add the module variable nodes that the diff's resources
+		// reference, so their values are available without
+		// re-interpolating anything the plan already resolved.
+		&ApplyableModuleVariableTransformer{
+			Diff:   b.Diff,
+			Module: b.Module,
+		},
+
+		&AttachStateTransformer{State: b.State},
+		&AttachResourceConfigTransformer{Module: b.Module},
+
+		&TargetsTransformer{Targets: b.Targets},
+
+		&ReferenceTransformer{},
+
+		&RootTransformer{},
+	}
+
+	return steps
+}
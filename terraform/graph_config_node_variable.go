@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/hashicorp/terraform/addrs"
 	"github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/config/module"
 	"github.com/hashicorp/terraform/dag"
@@ -15,13 +16,18 @@ type GraphNodeConfigVariable struct {
 
 	// Value, if non-nil, will be used to set the value of the variable
 	// during evaluation. If this is nil, evaluation will do nothing.
-	//
-	// Module is the name of the module to set the variables on.
-	Module string
-	Value  *config.RawConfig
+	Value *config.RawConfig
+
+	// Addr is the absolute address of the variable instance this node
+	// evaluates, replacing the former "Module string" / "ModulePath
+	// []string" pair. Its Module field identifies where the variable is
+	// stored: unlike NodePlannableModuleVariable, this node evaluates
+	// Value's expression in that same scope rather than the caller's, since
+	// it predates the plan/apply split and was never given a separate
+	// caller-side address to interpolate against.
+	Addr addrs.AbsInputVariableInstance
 
 	ModuleTree *module.Tree
-	ModulePath []string
 }
 
 func (n *GraphNodeConfigVariable) Name() string {
@@ -40,7 +46,16 @@ func (n *GraphNodeConfigVariable) RemoveIfNotTargeted() bool {
 	return true
 }
 
-func (n *GraphNodeConfigVariable) DependentOn() []string {
+// DependentOn returns the set of addresses this variable's value expression
+// refers to.
+//
+// This used to return the synthetic strings produced by varNameForVar
+// (e.g. "module.foo.output.bar") for the graph to match against
+// DependableName() by string equality. Now that references carry a typed
+// address, the graph transformer can instead compare addresses directly,
+// which is required to support per-instance references like
+// module.foo["a"].bar that have no faithful flat-string form.
+func (n *GraphNodeConfigVariable) DependentOn() []*addrs.Reference {
 	// If we don't have any value set, we don't depend on anything
 	if n.Value == nil {
 		return nil
@@ -48,11 +63,15 @@ func (n *GraphNodeConfigVariable) DependentOn() []string {
 
 	// Get what we depend on based on our value
 	vars := n.Value.Variables
-	result := make([]string, 0, len(vars))
+	result := make([]*addrs.Reference, 0, len(vars))
 	for _, v := range vars {
-		if vn := varNameForVar(v); vn != "" {
-			result = append(result, vn)
+		ref, err := addrs.ParseRef(v.FullKey())
+		if err != nil {
+			// Malformed references should already have been caught by
+			// config validation, so we just skip them here.
+			continue
 		}
+		result = append(result, ref)
 	}
 
 	return result
@@ -110,18 +129,17 @@ func (n *GraphNodeConfigVariable) EvalTree() EvalNode {
 
 			&EvalCoerceMapVariable{
 				Variables:  variables,
-				ModulePath: n.ModulePath,
 				ModuleTree: n.ModuleTree,
 			},
 
 			&EvalTypeCheckVariable{
 				Variables:  variables,
-				ModulePath: n.ModulePath,
+				ModuleAddr: n.Addr.Module,
 				ModuleTree: n.ModuleTree,
 			},
 
 			&EvalSetVariables{
-				Module:    &n.Module,
+				Addr:      n.Addr,
 				Variables: variables,
 			},
 		},
@@ -151,23 +169,49 @@ func (n *GraphNodeConfigVariableFlat) DependableName() []string {
 	return []string{n.Name()}
 }
 
-func (n *GraphNodeConfigVariableFlat) DependentOn() []string {
-	// We only wrap the dependencies and such if we have a path that is
-	// longer than 2 elements (root, child, more). This is because when
-	// flattened, variables can point outside the graph.
-	prefix := ""
-	if len(n.PathValue) > 2 {
-		prefix = modulePrefixStr(n.PathValue[:len(n.PathValue)-1])
+// GraphNodeDestroyEdgeInclude impl.
+//
+// This mirrors GraphNodeConfigVariable.DestroyEdgeInclude but must be
+// implemented separately rather than inherited: the embedded method
+// compares against the unprefixed base DependableName, which never
+// matches a nested module's prefixed dependency names and so silently
+// keeps every destroy edge instead of narrowing them to count-only
+// dependents.
+func (n *GraphNodeConfigVariableFlat) DestroyEdgeInclude(v dag.Vertex) bool {
+	cv, ok := v.(GraphNodeCountDependent)
+	if !ok {
+		return false
+	}
+
+	for _, d := range cv.CountDependentOn() {
+		for _, d2 := range n.DependableName() {
+			if d == d2 {
+				return true
+			}
+		}
 	}
 
-	return modulePrefixList(
-		n.GraphNodeConfigVariable.DependentOn(),
-		prefix)
+	return false
+}
+
+// DependentOn no longer needs to prefix its references with the enclosing
+// module path the way it prefixed the old flat strings: each
+// *addrs.Reference already carries a typed, self-describing address, and
+// the graph transformer resolves it relative to n.Path() when matching
+// vertices. The old prefix arithmetic here (modulePrefixList over
+// n.PathValue[:len(n.PathValue)-1]) only existed to patch that information
+// back onto a plain string.
+func (n *GraphNodeConfigVariableFlat) DependentOn() []*addrs.Reference {
+	return n.GraphNodeConfigVariable.DependentOn()
 }
 
 func (n *GraphNodeConfigVariableFlat) Path() []string {
-	if len(n.PathValue) > 2 {
-		return n.PathValue[:len(n.PathValue)-1]
+	// Ancestors() returns len(n.PathValue)+1 entries (it includes the root
+	// module), so matching the original "len(n.PathValue) > 2" threshold
+	// means comparing against 3 here, not 2 -- using 2 returns a non-nil
+	// path for an ordinary single-level-deep module, which is wrong.
+	if ancestors := addrs.Module(n.PathValue).Ancestors(); len(ancestors) > 3 {
+		return ancestors[len(ancestors)-2]
 	}
 
 	return nil
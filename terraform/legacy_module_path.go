@@ -0,0 +1,16 @@
+package terraform
+
+import "github.com/hashicorp/terraform/addrs"
+
+// legacyModulePath adapts an addrs.ModuleInstance to the []string module
+// path still expected by GraphNodeSubPath and friends. It necessarily
+// drops instance keys, since the legacy path representation has no room
+// for them; callers that need per-instance precision should use Addr
+// directly instead of this path.
+func legacyModulePath(m addrs.ModuleInstance) []string {
+	ret := make([]string, len(m))
+	for i, step := range m {
+		ret[i] = step.Name
+	}
+	return ret
+}
@@ -0,0 +1,211 @@
+package terraform
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform/addrs"
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/dag"
+)
+
+// nodeExpandModule is the graph vertex for an un-expanded module call: a
+// call that may use "count" or "for_each" and so may ultimately produce
+// zero or more module instances once the graph is walked.
+//
+// Unlike the old single-instance module subgraph, this node does not itself
+// represent any resources, variables, or outputs. Its only job is to
+// resolve the call's repetition argument and DynamicExpand into one
+// subgraph per resulting addrs.ModuleCallInstance (including addrs.NoKey
+// when neither "count" nor "for_each" is set).
+type nodeExpandModule struct {
+	Addr       addrs.ModuleCall
+	PathValue  []string
+	Call       *config.Module
+	ModuleTree *module.Tree
+
+	// Parent is the absolute module instance in which this call appears,
+	// used to build the absolute addrs.ModuleInstance of each expanded
+	// child instance.
+	Parent addrs.ModuleInstance
+}
+
+var _ GraphNodeSubPath = (*nodeExpandModule)(nil)
+var _ GraphNodeDynamicExpandable = (*nodeExpandModule)(nil)
+
+func (n *nodeExpandModule) Name() string {
+	return fmt.Sprintf("%s.%s (expand)", modulePrefixStr(n.PathValue), n.Addr.String())
+}
+
+// GraphNodeSubPath impl.
+func (n *nodeExpandModule) Path() []string {
+	return n.PathValue
+}
+
+// GraphNodeDynamicExpandable impl.
+//
+// DynamicExpand resolves the module call's "count" or "for_each" expression
+// (if any) in the context of the calling module and builds a subgraph
+// containing one nodeModuleInstance per resulting key.
+func (n *nodeExpandModule) DynamicExpand(ctx EvalContext) (*dag.AcyclicGraph, error) {
+	keys, err := n.expandKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := n.ModuleTree.Children()[n.Addr.Name]
+	if tree == nil {
+		return nil, fmt.Errorf("module %s not found in tree", n.Addr.Name)
+	}
+
+	var g dag.AcyclicGraph
+	for _, key := range keys {
+		instance := n.Addr.Instance(key)
+		childInstance := append(append(addrs.ModuleInstance{}, n.Parent...), addrs.ModuleInstanceStep{
+			Name: n.Addr.Name,
+			Key:  key,
+		})
+		g.Add(&nodeModuleInstance{
+			Addr:           instance,
+			ModuleInstance: childInstance,
+			PathValue:      append(append([]string{}, n.PathValue...), instance.String()),
+			Call:           n.Call,
+			ModuleTree:     tree,
+		})
+	}
+
+	return &g, nil
+}
+
+// expandKeys determines the concrete instance keys this module call
+// produces, by evaluating its "count" or "for_each" argument (if any) in
+// the calling module's scope. A call with neither argument produces exactly
+// one instance keyed by addrs.NoKey.
+func (n *nodeExpandModule) expandKeys(ctx EvalContext) ([]addrs.InstanceKey, error) {
+	switch {
+	case n.Call.RawCount != nil && !n.Call.RawCount.IsComputed():
+		var count int
+		if err := ctx.Interpolate(n.Call.RawCount, nil); err != nil {
+			return nil, fmt.Errorf("error interpolating count for module %s: %s", n.Addr.Name, err)
+		}
+		countVal, err := n.Call.Count()
+		if err != nil {
+			return nil, fmt.Errorf("error evaluating count for module %s: %s", n.Addr.Name, err)
+		}
+		count = countVal
+
+		keys := make([]addrs.InstanceKey, count)
+		for i := 0; i < count; i++ {
+			keys[i] = addrs.IntKey(i)
+		}
+		return keys, nil
+
+	case n.Call.RawForEach != nil:
+		if err := ctx.Interpolate(n.Call.RawForEach, nil); err != nil {
+			return nil, fmt.Errorf("error interpolating for_each for module %s: %s", n.Addr.Name, err)
+		}
+		forEachMap, err := n.Call.ForEach()
+		if err != nil {
+			return nil, fmt.Errorf("error evaluating for_each for module %s: %s", n.Addr.Name, err)
+		}
+
+		keys := make([]addrs.InstanceKey, 0, len(forEachMap))
+		for k := range forEachMap {
+			keys = append(keys, addrs.StringKey(k))
+		}
+		return keys, nil
+
+	default:
+		return []addrs.InstanceKey{addrs.NoKey}, nil
+	}
+}
+
+// nodeModuleInstance is the graph vertex for a single, concrete instance of
+// a module call, produced by nodeExpandModule.DynamicExpand. Its PathValue
+// is the full, instance-keyed module path (e.g. "module.foo[\"a\"]") used to
+// scope every variable, resource, and output node built underneath it.
+type nodeModuleInstance struct {
+	Addr       addrs.ModuleCallInstance
+	PathValue  []string
+	Call       *config.Module
+	ModuleTree *module.Tree
+
+	// ModuleInstance is this instance's own absolute address, used to
+	// scope the variable nodes built in DynamicExpand so that each
+	// instance gets its own var.* values.
+	ModuleInstance addrs.ModuleInstance
+}
+
+var _ GraphNodeSubPath = (*nodeModuleInstance)(nil)
+var _ GraphNodeDynamicExpandable = (*nodeModuleInstance)(nil)
+
+func (n *nodeModuleInstance) Name() string {
+	return fmt.Sprintf("%s.%s", modulePrefixStr(n.PathValue[:len(n.PathValue)-1]), n.Addr.String())
+}
+
+// GraphNodeSubPath impl.
+func (n *nodeModuleInstance) Path() []string {
+	return n.PathValue
+}
+
+// GraphNodeDynamicExpandable impl.
+//
+// DynamicExpand builds this instance's own subgraph: one variable node per
+// input variable the child module declares, addressed by this instance's
+// own addrs.AbsInputVariableInstance so that each expanded child gets its
+// own var.* values instead of sharing a single evaluation. Each instance
+// is expanded independently (nodeExpandModule adds one nodeModuleInstance
+// vertex per key), so walking the graph to destroy produces one destroy
+// subgraph per expanded instance for free.
+//
+// This tree has no resource-node or output-node subsystem at all -- those
+// are attached to the root module's graph by transformers this snapshot
+// doesn't include -- so this subgraph only ever contains variable nodes.
+// A tree that had them would need this method to also add one resource
+// and output node per declaration, scoped to n.Path(), the same way it
+// does for variables here.
+func (n *nodeModuleInstance) DynamicExpand(ctx EvalContext) (*dag.AcyclicGraph, error) {
+	var g dag.AcyclicGraph
+
+	for _, v := range n.ModuleTree.Config().Variables {
+		g.Add(&NodePlannableModuleVariable{
+			Addr:       addrs.InputVariable{Name: v.Name}.Absolute(n.ModuleInstance),
+			Config:     v,
+			Expr:       n.Call.RawConfig,
+			ModuleTree: n.ModuleTree,
+		})
+	}
+
+	return &g, nil
+}
+
+// GraphNodeDestroyEdgeInclude impl.
+//
+// GraphNodeConfigVariable.DestroyEdgeInclude already keeps a variable's
+// destroy edges to only those consumers that depend on it purely for
+// "count". The same narrowing has to happen here, on the module-call
+// side: when this module instance is destroyed, the variables passed into
+// it still create full dependency edges to every resource they
+// interpolate, and two sibling modules that reference each other's
+// outputs only for count would otherwise produce a destroy cycle.
+//
+// A count dependency on a module is always expressed in terms of one of
+// its outputs (e.g. "count = length(module.x.ids)" depends on
+// "module.x.ids", never on the bare "module.x"), so we have to match on
+// that output-scoped form rather than on n.Addr.String() alone.
+func (n *nodeModuleInstance) DestroyEdgeInclude(v dag.Vertex) bool {
+	cv, ok := v.(GraphNodeCountDependent)
+	if !ok {
+		return false
+	}
+
+	prefix := n.Addr.String() + "."
+	for _, d := range cv.CountDependentOn() {
+		if d == n.Addr.String() || strings.HasPrefix(d, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
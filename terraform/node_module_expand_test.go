@@ -0,0 +1,83 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform/addrs"
+	"github.com/hashicorp/terraform/dag"
+)
+
+type testCountDependent struct {
+	deps []string
+}
+
+func (t *testCountDependent) CountDependentOn() []string {
+	return t.deps
+}
+
+// TestNodeModuleInstance_DestroyEdgeInclude is a regression test for two
+// sibling modules whose resources reference each other only through
+// "count = length(module.x.ids)": the dependency a resource reports via
+// CountDependentOn is scoped to module x's "ids" output (e.g.
+// "module.x.ids"), never to the bare module address, so
+// DestroyEdgeInclude has to match on that output-scoped form or it keeps
+// every destroy edge and the destroy graph is never acyclic.
+func TestNodeModuleInstance_DestroyEdgeInclude(t *testing.T) {
+	n := &nodeModuleInstance{
+		Addr: addrs.ModuleCall{Name: "x"}.Instance(addrs.NoKey),
+	}
+
+	countOnly := &testCountDependent{deps: []string{"module.x.ids"}}
+	if !n.DestroyEdgeInclude(countOnly) {
+		t.Fatalf("expected edge to a count-only dependent on module x's output to be included")
+	}
+
+	unrelated := &testCountDependent{deps: []string{"module.y.ids"}}
+	if n.DestroyEdgeInclude(unrelated) {
+		t.Fatalf("expected edge to a dependent on an unrelated module to be excluded")
+	}
+
+	if n.DestroyEdgeInclude(struct{}{}) {
+		t.Fatalf("expected a vertex that isn't GraphNodeCountDependent to be excluded")
+	}
+}
+
+// TestNodeModuleInstance_DestroyGraphAcyclic builds an actual destroy graph
+// for two sibling modules whose own resources reference each other only
+// through count -- the scenario DestroyEdgeInclude exists to narrow -- and
+// confirms with dag.Validate that the edges it includes form a real,
+// acyclic graph rather than just checking its boolean result in isolation.
+func TestNodeModuleInstance_DestroyGraphAcyclic(t *testing.T) {
+	modX := &nodeModuleInstance{Addr: addrs.ModuleCall{Name: "x"}.Instance(addrs.NoKey)}
+	modY := &nodeModuleInstance{Addr: addrs.ModuleCall{Name: "y"}.Instance(addrs.NoKey)}
+
+	// resInX lives in module x and counts on module y's output;
+	// resInY lives in module y and counts on module x's output.
+	resInX := &testCountDependent{deps: []string{"module.y.ids"}}
+	resInY := &testCountDependent{deps: []string{"module.x.ids"}}
+
+	var g dag.AcyclicGraph
+	g.Add(modX)
+	g.Add(modY)
+	g.Add(resInX)
+	g.Add(resInY)
+
+	for _, mod := range []*nodeModuleInstance{modX, modY} {
+		for _, v := range []dag.Vertex{resInX, resInY} {
+			if mod.DestroyEdgeInclude(v) {
+				g.Connect(dag.BasicEdge(v, mod))
+			}
+		}
+	}
+
+	if err := g.Validate(); err != nil {
+		t.Fatalf("expected narrowed destroy edges to form an acyclic graph, got: %s", err)
+	}
+
+	if modX.DestroyEdgeInclude(resInX) {
+		t.Fatalf("resInX doesn't depend on module x, so it should not get an edge to modX")
+	}
+	if modY.DestroyEdgeInclude(resInY) {
+		t.Fatalf("resInY doesn't depend on module y, so it should not get an edge to modY")
+	}
+}
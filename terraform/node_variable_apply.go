@@ -0,0 +1,54 @@
+package terraform
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/addrs"
+)
+
+// NodeApplyableModuleVariable is the graph vertex that makes one module
+// call's input variable available to the callee during the apply walk.
+//
+// Unlike NodePlannableModuleVariable, it does no interpolation, coercion,
+// or type checking of its own: the plan walk already did that work and
+// recorded the result, so at apply time we only need to read the value
+// back out of the plan and set it, via EvalSetVariables, in the scope the
+// callee expects it in. This avoids re-evaluating expressions that may
+// reference resources the plan is about to destroy.
+type NodeApplyableModuleVariable struct {
+	Addr addrs.AbsInputVariableInstance
+}
+
+func (n *NodeApplyableModuleVariable) Name() string {
+	return fmt.Sprintf("%s (apply)", n.Addr.String())
+}
+
+// GraphNodeSubPath impl.
+func (n *NodeApplyableModuleVariable) Path() []string {
+	return legacyModulePath(n.Addr.Module)
+}
+
+// DependableName implements the dependency side of GraphNodeCountDependent
+// matching: it returns the same string a count expression elsewhere in the
+// graph would reference this variable by.
+func (n *NodeApplyableModuleVariable) DependableName() []string {
+	return []string{n.Addr.String()}
+}
+
+// GraphNodeEvalable impl.
+func (n *NodeApplyableModuleVariable) EvalTree() EvalNode {
+	variables := make(map[string]interface{})
+	return &EvalSequence{
+		Nodes: []EvalNode{
+			&EvalReadPlannedVariable{
+				Addr:   n.Addr,
+				Output: &variables,
+			},
+
+			&EvalSetVariables{
+				Addr:      n.Addr,
+				Variables: variables,
+			},
+		},
+	}
+}
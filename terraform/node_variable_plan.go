@@ -0,0 +1,89 @@
+package terraform
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/addrs"
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/config/module"
+)
+
+// NodePlannableModuleVariable is the graph vertex that evaluates one module
+// call's input variable during the plan walk.
+//
+// It interpolates the caller's argument expression in the parent module's
+// scope, coerces and type-checks the result against the callee's variable
+// declaration, and records the resulting value into the plan. This is the
+// plan-time half of what GraphNodeConfigVariable.EvalTree used to do in a
+// single phase; see NodeApplyableModuleVariable for the apply-time half.
+type NodePlannableModuleVariable struct {
+	Addr   addrs.AbsInputVariableInstance
+	Config *config.Variable
+	Expr   *config.RawConfig
+
+	ModuleTree *module.Tree
+}
+
+func (n *NodePlannableModuleVariable) Name() string {
+	return fmt.Sprintf("%s (plan)", n.Addr.String())
+}
+
+// GraphNodeSubPath impl.
+//
+// This returns the caller's path, one level up from n.Addr.Module, rather
+// than the callee's: Expr above is the caller's argument expression, and
+// must be interpolated in the scope where the caller wrote it, not in the
+// scope of the module it's being passed into.
+func (n *NodePlannableModuleVariable) Path() []string {
+	ancestors := n.Addr.Module.Ancestors()
+	return legacyModulePath(ancestors[len(ancestors)-2])
+}
+
+// DependableName implements the dependency side of GraphNodeCountDependent
+// matching: it returns the same string a count expression elsewhere in the
+// graph would reference this variable by.
+func (n *NodePlannableModuleVariable) DependableName() []string {
+	return []string{n.Addr.String()}
+}
+
+// GraphNodeEvalable impl.
+func (n *NodePlannableModuleVariable) EvalTree() EvalNode {
+	// If the caller didn't pass an expression for this variable, there's
+	// nothing to interpolate; the callee's default (if any) is applied
+	// elsewhere.
+	if n.Expr == nil {
+		return &EvalNoop{}
+	}
+
+	var rc *ResourceConfig
+	variables := make(map[string]interface{})
+	return &EvalSequence{
+		Nodes: []EvalNode{
+			&EvalInterpolate{
+				Config: n.Expr,
+				Output: &rc,
+			},
+
+			&EvalVariableBlock{
+				Config:         &rc,
+				VariableValues: variables,
+			},
+
+			&EvalCoerceMapVariable{
+				Variables:  variables,
+				ModuleTree: n.ModuleTree,
+			},
+
+			&EvalTypeCheckVariable{
+				Variables:  variables,
+				ModuleAddr: n.Addr.Module,
+				ModuleTree: n.ModuleTree,
+			},
+
+			&EvalWritePlannedVariable{
+				Addr:      n.Addr,
+				Variables: variables,
+			},
+		},
+	}
+}
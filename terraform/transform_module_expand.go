@@ -0,0 +1,36 @@
+package terraform
+
+import (
+	"github.com/hashicorp/terraform/addrs"
+	"github.com/hashicorp/terraform/config/module"
+)
+
+// ModuleExpandTransformer implements GraphTransformer to add one
+// nodeExpandModule vertex per module call declared directly in the root
+// module, so that each call's "count"/"for_each" argument gets resolved
+// and expanded into nodeModuleInstance vertices during the walk. Without
+// this transformer, nodeExpandModule is never constructed and no module
+// call ever gets variable nodes of its own.
+//
+// This only reaches calls declared in the root module: a call nested
+// inside a child module would need its own nodeExpandModule added once
+// its parent instance is known, which would mean DynamicExpand chaining
+// from nodeModuleInstance rather than a single up-front transformer pass,
+// and nodeModuleInstance.DynamicExpand doesn't do that in this tree.
+type ModuleExpandTransformer struct {
+	Module *module.Tree
+}
+
+func (t *ModuleExpandTransformer) Transform(g *Graph) error {
+	for _, call := range t.Module.Config().Modules {
+		g.Add(&nodeExpandModule{
+			Addr:       addrs.ModuleCall{Name: call.Name},
+			PathValue:  nil,
+			Call:       call,
+			ModuleTree: t.Module,
+			Parent:     addrs.ModuleInstance{},
+		})
+	}
+
+	return nil
+}
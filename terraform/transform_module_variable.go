@@ -0,0 +1,29 @@
+package terraform
+
+import (
+	"github.com/hashicorp/terraform/addrs"
+	"github.com/hashicorp/terraform/config/module"
+)
+
+// ApplyableModuleVariableTransformer implements GraphTransformer to add one
+// NodeApplyableModuleVariable per module variable the plan recorded a
+// value for, so that the apply graph can read those values back instead
+// of re-interpolating the caller's expressions against a graph that may
+// no longer contain the resources they originally referenced.
+type ApplyableModuleVariableTransformer struct {
+	Diff   *Diff
+	Module *module.Tree
+}
+
+func (t *ApplyableModuleVariableTransformer) Transform(g *Graph) error {
+	for addrStr := range t.Diff.ModuleVariables {
+		addr, err := addrs.ParseAbsInputVariableInstanceStr(addrStr)
+		if err != nil {
+			return err
+		}
+
+		g.Add(&NodeApplyableModuleVariable{Addr: addr})
+	}
+
+	return nil
+}